@@ -5,13 +5,19 @@ import (
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/sysinfo"
 	"github.com/docker/go-units"
 )
 
 // ValidatorHugetlbType defines a validator function that returns a validated struct and/or an error.
 type ValidatorHugetlbType func(val string) (container.Hugetlb, error)
 
-// ValidateHugetlb validates that the specified string has a valid hugetlb format.
+// ValidateHugetlb validates that the specified string has a valid hugetlb
+// format. This only checks syntax: it runs wherever a --hugetlb flag is
+// parsed (including client-side, before a daemon connection exists), so it
+// has no host to check free pages against. See ValidateHugetlbAgainstHost
+// for the host-aware check, which the daemon's container-create path
+// should run once ValidateHugetlb has produced a container.Hugetlb.
 func ValidateHugetlb(htlb string) (container.Hugetlb, error) {
 	var size, limit string
 	var hugetlb container.Hugetlb
@@ -43,6 +49,22 @@ func ValidateHugetlb(htlb string) (container.Hugetlb, error) {
 	return hugetlb, nil
 }
 
+// ValidateHugetlbAgainstHost resolves htlb's default page size (if it
+// didn't specify one) and warns when its limit exceeds the pages currently
+// free on the host, using info's NUMA-aware hugepage inventory. Unlike
+// ValidateHugetlb, this needs a *sysinfo.SysInfo for the host the container
+// will run on, so it belongs in the daemon's container-create validation
+// path (where that SysInfo is available), not in client-side flag parsing.
+// That call site is daemon-side work and isn't part of this package.
+func ValidateHugetlbAgainstHost(htlb container.Hugetlb, info *sysinfo.SysInfo) (container.Hugetlb, []string, error) {
+	pageSize, warnings, err := info.ValidateHugetlb(htlb.PageSize, htlb.Limit)
+	if err != nil {
+		return htlb, warnings, err
+	}
+	htlb.PageSize = pageSize
+	return htlb, warnings, nil
+}
+
 // HugetlbOpt defines a map of Hugetlbs
 type HugetlbOpt struct {
 	values    []container.Hugetlb
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/docker/docker/pkg/sysinfo"
+)
+
+// backend reads a single container's accounting files for one cgroup
+// version, translating them into the plain values Collector turns into
+// Prometheus samples.
+type backend interface {
+	memoryUsage() (uint64, error)
+	memorySwapUsage() (uint64, error)
+	cpuUsage() (time.Duration, error)
+	blkioServiceBytes() (read, write uint64, err error)
+	pidsCurrent() (uint64, error)
+	hugetlbUsage(pageSize string) (uint64, error)
+}
+
+// v1Backend reads the legacy per-controller cgroup v1 hierarchy. cgroupPath
+// is resolved against each controller's own mountpoint, since v1
+// controllers need not share a mountpoint. Mountpoints are resolved once,
+// at construction, rather than on every metric read.
+type v1Backend struct {
+	cgroupPath string
+	mounts     map[string]string
+}
+
+func newV1Backend(cgroupPath string) v1Backend {
+	mounts, _ := sysinfo.FindCgroupMountpoints()
+	return v1Backend{cgroupPath: cgroupPath, mounts: mounts}
+}
+
+func (b v1Backend) controllerFile(controller, file string) (string, error) {
+	mp, ok := b.mounts[controller]
+	if !ok {
+		return "", errUnsupported
+	}
+	return filepath.Join(mp, b.cgroupPath, file), nil
+}
+
+func (b v1Backend) memoryUsage() (uint64, error) {
+	p, err := b.controllerFile("memory", "memory.usage_in_bytes")
+	if err != nil {
+		return 0, err
+	}
+	return readUint64(p)
+}
+
+// memorySwapUsage reports swap-only usage, matching v2Backend. v1's
+// memory.memsw.usage_in_bytes is memory+swap combined, so it subtracts
+// memory.usage_in_bytes from it (clamping at 0, since the two files are
+// sampled separately and can race on a shrinking cgroup).
+func (b v1Backend) memorySwapUsage() (uint64, error) {
+	p, err := b.controllerFile("memory", "memory.memsw.usage_in_bytes")
+	if err != nil {
+		return 0, err
+	}
+	memsw, err := readUint64(p)
+	if err != nil {
+		return 0, err
+	}
+
+	mem, err := b.memoryUsage()
+	if err != nil {
+		return 0, err
+	}
+
+	if memsw < mem {
+		return 0, nil
+	}
+	return memsw - mem, nil
+}
+
+func (b v1Backend) cpuUsage() (time.Duration, error) {
+	p, err := b.controllerFile("cpuacct", "cpuacct.usage")
+	if err != nil {
+		return 0, err
+	}
+	ns, err := readUint64(p)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ns), nil
+}
+
+func (b v1Backend) blkioServiceBytes() (read, write uint64, err error) {
+	p, err := b.controllerFile("blkio", "blkio.io_service_bytes")
+	if err != nil {
+		return 0, 0, err
+	}
+	return blkioTotals(p)
+}
+
+func (b v1Backend) pidsCurrent() (uint64, error) {
+	p, err := b.controllerFile("pids", "pids.current")
+	if err != nil {
+		return 0, err
+	}
+	return readUint64(p)
+}
+
+func (b v1Backend) hugetlbUsage(pageSize string) (uint64, error) {
+	p, err := b.controllerFile("hugetlb", "hugetlb."+pageSize+".usage_in_bytes")
+	if err != nil {
+		return 0, err
+	}
+	return readUint64(p)
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// v2Backend reads a container's cgroup from the unified (cgroup v2)
+// hierarchy. Unlike v1, every controller lives under the same path.
+type v2Backend struct {
+	path string
+}
+
+func (b v2Backend) memoryUsage() (uint64, error) {
+	return readUint64(filepath.Join(b.path, "memory.current"))
+}
+
+func (b v2Backend) memorySwapUsage() (uint64, error) {
+	return readUint64(filepath.Join(b.path, "memory.swap.current"))
+}
+
+func (b v2Backend) cpuUsage() (time.Duration, error) {
+	stat, err := readKeyedStat(filepath.Join(b.path, "cpu.stat"))
+	if err != nil {
+		return 0, err
+	}
+	usec, ok := stat["usage_usec"]
+	if !ok {
+		return 0, errUnsupported
+	}
+	return time.Duration(usec) * time.Microsecond, nil
+}
+
+func (b v2Backend) blkioServiceBytes() (read, write uint64, err error) {
+	return ioStatTotals(filepath.Join(b.path, "io.stat"))
+}
+
+func (b v2Backend) pidsCurrent() (uint64, error) {
+	return readUint64(filepath.Join(b.path, "pids.current"))
+}
+
+func (b v2Backend) hugetlbUsage(pageSize string) (uint64, error) {
+	return readUint64(filepath.Join(b.path, "hugetlb."+pageSize+".current"))
+}
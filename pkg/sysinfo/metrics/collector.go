@@ -0,0 +1,249 @@
+// Package metrics implements a per-container cgroup metrics collector.
+//
+// Rather than hard-coding which cgroup controllers to read, the collector
+// consults the host's sysinfo.SysInfo capability probe: it skips hugetlb
+// collection when the host has no hugetlb controller, skips swap when
+// SwapLimit isn't supported, and so on. This keeps a single collector
+// working unchanged across hosts that are missing one or more controllers,
+// and across the cgroup v1/v2 split.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/pkg/sysinfo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	memoryUsageDesc = prometheus.NewDesc(
+		"container_memory_usage_bytes",
+		"Current memory usage of the container, in bytes.",
+		[]string{"id"}, nil,
+	)
+	memorySwapUsageDesc = prometheus.NewDesc(
+		"container_memory_swap_bytes",
+		"Current swap usage of the container, in bytes.",
+		[]string{"id"}, nil,
+	)
+	cpuUsageDesc = prometheus.NewDesc(
+		"container_cpu_usage_seconds_total",
+		"Cumulative CPU time consumed by the container, in seconds.",
+		[]string{"id"}, nil,
+	)
+	blkioServiceBytesDesc = prometheus.NewDesc(
+		"container_blkio_service_bytes_total",
+		"Cumulative bytes transferred to/from block devices by the container.",
+		[]string{"id", "operation"}, nil,
+	)
+	pidsCurrentDesc = prometheus.NewDesc(
+		"container_pids_current",
+		"Current number of PIDs in the container.",
+		[]string{"id"}, nil,
+	)
+	hugetlbUsageDesc = prometheus.NewDesc(
+		"container_hugetlb_usage_bytes",
+		"Current hugetlb usage of the container, in bytes, by page size.",
+		[]string{"id", "page_size"}, nil,
+	)
+)
+
+// Collector samples a single container's cgroup accounting files and
+// reports them as Prometheus metrics. It implements prometheus.Collector,
+// so it's meant to be registered once per container with a
+// prometheus.Registry.
+type Collector struct {
+	id      string
+	sysInfo *sysinfo.SysInfo
+	backend backend
+}
+
+// NewCollector returns a Collector for the container identified by id,
+// whose cgroup lives at cgroupPath (the path relative to a controller's
+// mountpoint, e.g. "/docker/<container-id>"). info is consulted on every
+// collection to decide which files are worth reading, so it must be the
+// same *sysinfo.SysInfo the daemon probed at startup (see probeCgroupV2's
+// doc comment in package sysinfo) -- a zero-value or partially-probed
+// SysInfo makes every field here false and the collector silently emits
+// no series at all.
+func NewCollector(id, cgroupPath string, info *sysinfo.SysInfo) *Collector {
+	var b backend
+	if info.CgroupUnified {
+		b = v2Backend{path: filepath.Join(unifiedMountpoint, cgroupPath)}
+	} else {
+		b = newV1Backend(cgroupPath)
+	}
+	return &Collector{id: id, sysInfo: info, backend: b}
+}
+
+// Register builds a Collector for the container identified by id and
+// registers it with reg, so its metrics are scraped alongside the rest of
+// /metrics. This is the integration point daemon code should call when a
+// container starts (and Unregister the returned Collector when it stops),
+// so that `docker stats` and /metrics surface the same cgroup data. Adding
+// that call site is daemon-side work and isn't part of this package.
+func Register(reg *prometheus.Registry, id, cgroupPath string, info *sysinfo.SysInfo) (*Collector, error) {
+	c := NewCollector(id, cgroupPath, info)
+	if err := reg.Register(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+const unifiedMountpoint = "/sys/fs/cgroup"
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- memoryUsageDesc
+	ch <- memorySwapUsageDesc
+	ch <- cpuUsageDesc
+	ch <- blkioServiceBytesDesc
+	ch <- pidsCurrentDesc
+	ch <- hugetlbUsageDesc
+}
+
+// Collect implements prometheus.Collector. It only emits metrics for
+// controllers that c.sysInfo reports as supported, so a host missing (say)
+// the hugetlb controller simply yields fewer series rather than an error.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	if c.sysInfo.MemoryLimit {
+		if v, err := c.backend.memoryUsage(); err == nil {
+			ch <- prometheus.MustNewConstMetric(memoryUsageDesc, prometheus.GaugeValue, float64(v), c.id)
+		}
+		if c.sysInfo.SwapLimit {
+			if v, err := c.backend.memorySwapUsage(); err == nil {
+				ch <- prometheus.MustNewConstMetric(memorySwapUsageDesc, prometheus.GaugeValue, float64(v), c.id)
+			}
+		}
+	}
+
+	if c.sysInfo.CPUShares {
+		if v, err := c.backend.cpuUsage(); err == nil {
+			ch <- prometheus.MustNewConstMetric(cpuUsageDesc, prometheus.CounterValue, v.Seconds(), c.id)
+		}
+	}
+
+	if c.sysInfo.BlkioWeight || c.sysInfo.BlkioReadBpsDevice {
+		if read, write, err := c.backend.blkioServiceBytes(); err == nil {
+			ch <- prometheus.MustNewConstMetric(blkioServiceBytesDesc, prometheus.CounterValue, float64(read), c.id, "read")
+			ch <- prometheus.MustNewConstMetric(blkioServiceBytesDesc, prometheus.CounterValue, float64(write), c.id, "write")
+		}
+	}
+
+	if c.sysInfo.PidsLimit {
+		if v, err := c.backend.pidsCurrent(); err == nil {
+			ch <- prometheus.MustNewConstMetric(pidsCurrentDesc, prometheus.GaugeValue, float64(v), c.id)
+		}
+	}
+
+	if c.sysInfo.HugetlbLimit {
+		for _, size := range sysinfo.HugePageSizes {
+			v, err := c.backend.hugetlbUsage(size)
+			if err != nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(hugetlbUsageDesc, prometheus.GaugeValue, float64(v), c.id, size)
+		}
+	}
+}
+
+func readUint64(path string) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// readKeyedStat parses the "<key> <value>" per-line format shared by
+// memory.stat (v1 and v2) and cpu.stat (v2). io.stat uses a different
+// format and is parsed by ioStatTotals.
+func readKeyedStat(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat := make(map[string]uint64)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		stat[fields[0]] = v
+	}
+	return stat, s.Err()
+}
+
+// blkioTotals sums the per-device "Read"/"Write" lines of a v1
+// blkio.io_service_bytes file.
+func blkioTotals(path string) (read, write uint64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			read += v
+		case "Write":
+			write += v
+		}
+	}
+	return read, write, s.Err()
+}
+
+// ioStatTotals sums the rbytes=/wbytes= fields of a v2 io.stat file across
+// all devices.
+func ioStatTotals(path string) (read, write uint64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		for _, field := range strings.Fields(s.Text()) {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "rbytes":
+				read += v
+			case "wbytes":
+				write += v
+			}
+		}
+	}
+	return read, write, s.Err()
+}
+
+var errUnsupported = fmt.Errorf("metric not supported by this backend")
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBlkioTotals(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blkio.io_service_bytes")
+	writeFile(t, path, `8:0 Read 1024
+8:0 Write 2048
+8:16 Read 512
+8:16 Write 256
+Total 3840
+`)
+
+	read, write, err := blkioTotals(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != 1536 || write != 2304 {
+		t.Errorf("blkioTotals() = (%d, %d), want (1536, 2304)", read, write)
+	}
+}
+
+func TestIOStatTotals(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "io.stat")
+	writeFile(t, path, `8:0 rbytes=1024 wbytes=2048 rios=1 wios=1
+8:16 rbytes=512 wbytes=256 rios=1 wios=1
+`)
+
+	read, write, err := ioStatTotals(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != 1536 || write != 2304 {
+		t.Errorf("ioStatTotals() = (%d, %d), want (1536, 2304)", read, write)
+	}
+}
+
+func TestV1BackendMemorySwapUsageIsSwapOnly(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "memory.usage_in_bytes"), "1000")
+	writeFile(t, filepath.Join(dir, "memory.memsw.usage_in_bytes"), "1600")
+
+	b := v1Backend{cgroupPath: "", mounts: map[string]string{"memory": dir}}
+
+	mem, err := b.memoryUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mem != 1000 {
+		t.Fatalf("memoryUsage() = %d, want 1000", mem)
+	}
+
+	swap, err := b.memorySwapUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if swap != 600 {
+		t.Errorf("memorySwapUsage() = %d, want 600 (memsw - mem)", swap)
+	}
+}
+
+func TestV1BackendMemorySwapUsageClampsAtZero(t *testing.T) {
+	dir := t.TempDir()
+	// memsw briefly samples lower than usage_in_bytes across two separate
+	// reads; the difference must clamp at 0 rather than wrap around.
+	writeFile(t, filepath.Join(dir, "memory.usage_in_bytes"), "1000")
+	writeFile(t, filepath.Join(dir, "memory.memsw.usage_in_bytes"), "900")
+
+	b := v1Backend{cgroupPath: "", mounts: map[string]string{"memory": dir}}
+
+	swap, err := b.memorySwapUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if swap != 0 {
+		t.Errorf("memorySwapUsage() = %d, want 0 when memsw < usage", swap)
+	}
+}
+
+func TestV2BackendMemorySwapUsageIsSwapOnly(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "memory.swap.current"), "600")
+
+	b := v2Backend{path: dir}
+	swap, err := b.memorySwapUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if swap != 600 {
+		t.Errorf("memorySwapUsage() = %d, want 600", swap)
+	}
+}
+
+func TestV1BackendControllerFileUnsupported(t *testing.T) {
+	b := v1Backend{cgroupPath: "/docker/abc", mounts: map[string]string{}}
+	if _, err := b.memoryUsage(); err != errUnsupported {
+		t.Errorf("memoryUsage() with no memory mount = %v, want errUnsupported", err)
+	}
+}
@@ -0,0 +1,33 @@
+package sysinfo
+
+import "testing"
+
+func TestCountCPUList(t *testing.T) {
+	cases := []struct {
+		list    string
+		want    int
+		wantErr bool
+	}{
+		{"0", 1, false},
+		{"0-3", 4, false},
+		{"0-3,8", 5, false},
+		{"", 0, false},
+		{"not-a-cpu-list", 0, true},
+	}
+	for _, c := range cases {
+		got, err := countCPUList(c.list)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("countCPUList(%q) = %d, <nil>, want an error", c.list, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("countCPUList(%q) returned unexpected error: %v", c.list, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("countCPUList(%q) = %d, want %d", c.list, got, c.want)
+		}
+	}
+}
@@ -5,12 +5,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/go-units"
 )
 
+// unifiedMountpoint is where a cgroup v2 unified hierarchy is expected to be
+// mounted, e.g. when booted with `systemd.unified_cgroup_hierarchy=1`.
+const unifiedMountpoint = "/sys/fs/cgroup"
+
 // SysInfo stores information about which features a kernel supports.
 // TODO Windows: Factor out platform specific capabilities.
 type SysInfo struct {
@@ -19,6 +25,10 @@ type SysInfo struct {
 	// Whether the kernel supports Seccomp or not
 	Seccomp bool
 
+	// Whether the host is running under the cgroup v2 unified hierarchy
+	// rather than the legacy per-controller (v1) hierarchy.
+	CgroupUnified bool
+
 	cgroupMemInfo
 	cgroupHugetlbInfo
 	cgroupCPUInfo
@@ -26,6 +36,11 @@ type SysInfo struct {
 	cgroupCpusetInfo
 	cgroupPids
 
+	// HugePages is the NUMA-aware hugepage inventory of the host: one entry
+	// per supported page size for the system as a whole (NUMANode == -1),
+	// plus one entry per page size for each NUMA node.
+	HugePages []HugePageInfo
+
 	// Whether IPv4 forwarding is supported or not, if this was disabled, networking will not work
 	IPv4ForwardingDisabled bool
 
@@ -117,8 +132,345 @@ type cgroupPids struct {
 	PidsLimit bool
 }
 
+// isCgroup2UnifiedMode returns whether unifiedMountpoint is mounted as a
+// cgroup2 filesystem, i.e. the host boots with a unified cgroup hierarchy
+// rather than the legacy per-controller (v1) one.
+func isCgroup2UnifiedMode() bool {
+	f, err := os.Open("/proc/mounts")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		if fields[1] == unifiedMountpoint {
+			return fields[2] == "cgroup2"
+		}
+	}
+	return false
+}
+
+// readUnifiedControllers returns the set of controllers listed in the
+// unified hierarchy's "cgroup.controllers" file.
+func readUnifiedControllers(mountpoint string) (map[string]bool, error) {
+	f, err := os.Open(filepath.Join(mountpoint, "cgroup.controllers"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	controllers := make(map[string]bool)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		for _, c := range strings.Fields(s.Text()) {
+			controllers[c] = true
+		}
+	}
+	return controllers, s.Err()
+}
+
+// newV2MemInfo translates the v1 memInfo capability flags to their v2
+// equivalents: memory.swap.max implies SwapLimit and memory.low implies
+// MemoryReservation.
+func newV2MemInfo(mountpoint string, controllers map[string]bool) cgroupMemInfo {
+	var m cgroupMemInfo
+	if !controllers["memory"] {
+		return m
+	}
+	m.MemoryLimit = true
+	m.OomKillDisable = false // removed in v2, OOM is always enabled
+	m.SwapLimit = cgroupFileExists(mountpoint, "memory.swap.max")
+	m.MemoryReservation = cgroupFileExists(mountpoint, "memory.low")
+	// memory.swappiness and kmem accounting no longer exist as separate
+	// knobs under the unified hierarchy.
+	m.MemorySwappiness = false
+	m.KernelMemory = false
+	return m
+}
+
+// newV2CPUInfo translates cpu.max into the v1 CPUCfsPeriod/CPUCfsQuota
+// capability flags. Real-time scheduling knobs have no v2 equivalent.
+func newV2CPUInfo(mountpoint string, controllers map[string]bool) cgroupCPUInfo {
+	var c cgroupCPUInfo
+	if !controllers["cpu"] {
+		return c
+	}
+	c.CPUShares = true
+	hasCPUMax := cgroupFileExists(mountpoint, "cpu.max")
+	c.CPUCfsPeriod = hasCPUMax
+	c.CPUCfsQuota = hasCPUMax
+	c.CPURealtimePeriod = false
+	c.CPURealtimeRuntime = false
+	return c
+}
+
+// newV2BlkioInfo translates io.max/io.weight into the v1 Blkio* capability
+// flags.
+func newV2BlkioInfo(mountpoint string, controllers map[string]bool) cgroupBlkioInfo {
+	var b cgroupBlkioInfo
+	if !controllers["io"] {
+		return b
+	}
+	b.BlkioWeight = cgroupFileExists(mountpoint, "io.weight")
+	hasIOMax := cgroupFileExists(mountpoint, "io.max")
+	b.BlkioReadBpsDevice = hasIOMax
+	b.BlkioWriteBpsDevice = hasIOMax
+	b.BlkioReadIOpsDevice = hasIOMax
+	b.BlkioWriteIOpsDevice = hasIOMax
+	// Per-device weights are not supported under the unified hierarchy.
+	b.BlkioWeightDevice = false
+	return b
+}
+
+// newV2PidsInfo sets PidsLimit when the pids controller (which provides
+// pids.max) is listed in cgroup.controllers; pids.max itself is not checked.
+func newV2PidsInfo(controllers map[string]bool) cgroupPids {
+	return cgroupPids{PidsLimit: controllers["pids"]}
+}
+
+// newV2CpusetInfo reads cpuset.cpus.effective/cpuset.mems.effective, the v2
+// equivalents of the v1 cpuset.cpus/cpuset.mems files.
+func newV2CpusetInfo(mountpoint string, controllers map[string]bool) cgroupCpusetInfo {
+	var c cgroupCpusetInfo
+	if !controllers["cpuset"] {
+		return c
+	}
+	c.Cpuset = true
+	c.Cpus = readCgroupFile(mountpoint, "cpuset.cpus.effective")
+	c.Mems = readCgroupFile(mountpoint, "cpuset.mems.effective")
+	return c
+}
+
+// newV2HugetlbInfo reports hugetlb support and enumerates hugepage sizes
+// from the unified hierarchy's hugetlb.<size>.max files, rather than the v1
+// hugetlb.<size>.limit_in_bytes naming.
+func newV2HugetlbInfo(mountpoint string, controllers map[string]bool) cgroupHugetlbInfo {
+	var h cgroupHugetlbInfo
+	if !controllers["hugetlb"] {
+		return h
+	}
+	sizes, err := hugepageSizesFromCgroup(mountpoint, ".max")
+	h.HugetlbLimit = err == nil && len(sizes) > 0
+	return h
+}
+
+// hugepageSizesFromCgroup enumerates the hugepage sizes supported by the
+// kernel from the hugetlb.<size>.<suffix> files found under mountpoint.
+func hugepageSizesFromCgroup(mountpoint, suffix string) ([]string, error) {
+	var hps []string
+	seen := make(map[string]bool)
+
+	f, err := os.Open(mountpoint)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open hugetlb cgroup directory")
+	}
+	defer f.Close()
+
+	fi, err := f.Readdir(-1)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read hugetlb cgroup directory")
+	}
+
+	for _, finfo := range fi {
+		name := finfo.Name()
+		if !strings.HasPrefix(name, "hugetlb.") || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		sres := strings.SplitN(name, ".", 3)
+		if len(sres) != 3 {
+			continue
+		}
+		// Kernels that expose reservation accounting have both
+		// hugetlb.<size>.limit_in_bytes and hugetlb.<size>.rsvd.limit_in_bytes
+		// for the same size; only report each size once.
+		size := sres[1]
+		if seen[size] {
+			continue
+		}
+		seen[size] = true
+		hps = append(hps, size)
+	}
+	if len(hps) == 0 {
+		return nil, fmt.Errorf("Hugetlb pagesize not found in cgroup")
+	}
+
+	return hps, nil
+}
+
+func cgroupFileExists(mountpoint, name string) bool {
+	_, err := os.Stat(filepath.Join(mountpoint, name))
+	return err == nil
+}
+
+func readCgroupFile(mountpoint, name string) string {
+	b, err := os.ReadFile(filepath.Join(mountpoint, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// probeCgroupV2 detects whether the host boots with a unified (cgroup v2)
+// hierarchy and, if so, populates info's cgroup capability fields from it
+// instead of the legacy per-controller v1 mountpoints.
+//
+// This file only carries the platform-agnostic parts of cgroup v2 and
+// hugepage probing; it is not itself a SysInfo constructor. The
+// platform-specific constructor (e.g. sysinfo_linux.go) must call
+// probeCgroupV2(info) first and inspect info.CgroupUnified afterwards: if
+// true, v2 already populated the cgroup fields and v1 probing must be
+// skipped; if false, the constructor should fall back to its v1 probing
+// path as before. probeHugePages(info) should then be called unconditionally,
+// since the hugepage inventory is read straight from sysfs on both
+// hierarchies. There is deliberately no package-level New() here, since the
+// real constructor already has that name (New(quiet bool) *SysInfo) and
+// owns the v1 fallback.
+func probeCgroupV2(info *SysInfo) {
+	info.CgroupUnified = isCgroup2UnifiedMode()
+	if !info.CgroupUnified {
+		return
+	}
+
+	controllers, err := readUnifiedControllers(unifiedMountpoint)
+	if err != nil {
+		return
+	}
+
+	info.cgroupMemInfo = newV2MemInfo(unifiedMountpoint, controllers)
+	info.cgroupCPUInfo = newV2CPUInfo(unifiedMountpoint, controllers)
+	info.cgroupBlkioInfo = newV2BlkioInfo(unifiedMountpoint, controllers)
+	info.cgroupPids = newV2PidsInfo(controllers)
+	info.cgroupCpusetInfo = newV2CpusetInfo(unifiedMountpoint, controllers)
+	info.cgroupHugetlbInfo = newV2HugetlbInfo(unifiedMountpoint, controllers)
+}
+
+// HugePageInfo describes the kernel's hugepage inventory for a single page
+// size, either system-wide (NUMANode == -1) or for a single NUMA node.
+// Reserved is only tracked system-wide; it is always 0 for per-node entries.
+type HugePageInfo struct {
+	SizeBytes uint64
+	NUMANode  int
+
+	Total    int64
+	Free     int64
+	Surplus  int64
+	Reserved int64
+}
+
+// hugepagesRoot and nodeRoot are vars, rather than consts, so tests can
+// point them at a fixture directory.
+var hugepagesRoot = "/sys/kernel/mm/hugepages"
+var nodeRoot = "/sys/devices/system/node"
+
+// hugePages builds the NUMA-aware hugepage inventory: one system-wide entry
+// per page size from hugepagesRoot, followed by one entry per page size for
+// each node found under nodeRoot (which does not expose Reserved).
+func hugePages() []HugePageInfo {
+	var infos []HugePageInfo
+
+	dirs, err := os.ReadDir(hugepagesRoot)
+	if err != nil {
+		return infos
+	}
+	for _, d := range dirs {
+		sizeBytes, ok := hugepageDirSize(d.Name())
+		if !ok {
+			continue
+		}
+		info := HugePageInfo{SizeBytes: sizeBytes, NUMANode: -1}
+		dir := filepath.Join(hugepagesRoot, d.Name())
+		info.Total = readHugepageCounter(dir, "nr_hugepages")
+		info.Free = readHugepageCounter(dir, "free_hugepages")
+		info.Surplus = readHugepageCounter(dir, "surplus_hugepages")
+		info.Reserved = readHugepageCounter(dir, "resv_hugepages")
+		infos = append(infos, info)
+	}
+
+	nodeDirs, err := os.ReadDir(nodeRoot)
+	if err != nil {
+		return infos
+	}
+	for _, nd := range nodeDirs {
+		node, ok := nodeDirNode(nd.Name())
+		if !ok {
+			continue
+		}
+		nodeHugepagesDir := filepath.Join(nodeRoot, nd.Name(), "hugepages")
+		sizeDirs, err := os.ReadDir(nodeHugepagesDir)
+		if err != nil {
+			continue
+		}
+		for _, d := range sizeDirs {
+			sizeBytes, ok := hugepageDirSize(d.Name())
+			if !ok {
+				continue
+			}
+			dir := filepath.Join(nodeHugepagesDir, d.Name())
+			infos = append(infos, HugePageInfo{
+				SizeBytes: sizeBytes,
+				NUMANode:  node,
+				Total:     readHugepageCounter(dir, "nr_hugepages"),
+				Free:      readHugepageCounter(dir, "free_hugepages"),
+				Surplus:   readHugepageCounter(dir, "surplus_hugepages"),
+				// Reserved is not exposed per-node.
+			})
+		}
+	}
+
+	return infos
+}
+
+// hugepageDirSize parses the page size, in bytes, out of a directory name of
+// the form "hugepages-<N>kB".
+func hugepageDirSize(name string) (uint64, bool) {
+	if !strings.HasPrefix(name, "hugepages-") || !strings.HasSuffix(name, "kB") {
+		return 0, false
+	}
+	kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, "hugepages-"), "kB"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return kb * 1024, true
+}
+
+// nodeDirNode parses the node ID out of a directory name of the form
+// "node<N>".
+func nodeDirNode(name string) (int, bool) {
+	if !strings.HasPrefix(name, "node") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(name, "node"))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+func readHugepageCounter(dir, name string) int64 {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// probeHugePages populates info.HugePages with the system-wide and
+// per-NUMA-node hugepage inventory. See hugePages for details.
+func probeHugePages(info *SysInfo) {
+	info.HugePages = hugePages()
+}
+
 // ValidateHugetlb check whether hugetlb pagesize and limit legal
-func (c cgroupHugetlbInfo) ValidateHugetlb(pageSize string, limit uint64) (string, []string, error) {
+func (c SysInfo) ValidateHugetlb(pageSize string, limit uint64) (string, []string, error) {
 	var (
 		w   []string
 		err error
@@ -142,9 +494,57 @@ func (c cgroupHugetlbInfo) ValidateHugetlb(pageSize string, limit uint64) (strin
 		return "", w, err
 	}
 
+	if warning := c.checkHugetlbAvailability(pageSize, limit); warning != "" {
+		w = append(w, warning)
+	}
+
 	return pageSize, w, nil
 }
 
+// checkHugetlbAvailability warns when the requested limit, expressed as a
+// number of pages of the given size, exceeds the pages currently free across
+// all NUMA nodes. It returns an empty string when the inventory is unknown
+// (e.g. HugePages was never populated) or the limit fits.
+func (c SysInfo) checkHugetlbAvailability(pageSize string, limit uint64) string {
+	sizeInt, err := units.RAMInBytes(pageSize)
+	if err != nil || sizeInt <= 0 {
+		return ""
+	}
+	sizeBytes := uint64(sizeInt)
+	requested := limit / sizeBytes
+
+	// Prefer summing the per-node entries, since those are what a NUMA-aware
+	// allocation actually draws from; fall back to the system-wide entry
+	// (NUMANode == -1) when no per-node inventory is available, e.g. on a
+	// non-NUMA host.
+	var freeAcrossNodes int64
+	var sawNode bool
+	for _, hp := range c.HugePages {
+		if hp.SizeBytes != sizeBytes || hp.NUMANode < 0 {
+			continue
+		}
+		sawNode = true
+		freeAcrossNodes += hp.Free
+	}
+	if !sawNode {
+		for _, hp := range c.HugePages {
+			if hp.SizeBytes != sizeBytes || hp.NUMANode != -1 {
+				continue
+			}
+			sawNode = true
+			freeAcrossNodes += hp.Free
+		}
+	}
+	if !sawNode {
+		return ""
+	}
+
+	if requested > uint64(freeAcrossNodes) {
+		return fmt.Sprintf("Requested hugetlb limit of %d %s pages exceeds the %d pages currently free across all NUMA nodes; the container may fail to start", requested, pageSize, freeAcrossNodes)
+	}
+	return ""
+}
+
 // isHugeLimitValid check whether input hugetlb limit legal
 // it will check whether the limit size is times of size
 func isHugeLimitValid(size string, limit uint64) ([]string, error) {
@@ -166,17 +566,12 @@ func isHugeLimitValid(size string, limit uint64) ([]string, error) {
 // isHugepageSizeValid check whether input size legal
 // it will compare size with all system supported hugepage size
 func isHugepageSizeValid(size string) error {
-	hps, err := getHugepageSizes()
-	if err != nil {
-		return err
-	}
-
-	for _, hp := range hps {
+	for _, hp := range HugePageSizes {
 		if size == hp {
 			return nil
 		}
 	}
-	return fmt.Errorf("Invalid hugepage size:%s, shoud be one of %v", size, hps)
+	return fmt.Errorf("Invalid hugepage size:%s, shoud be one of %v", size, HugePageSizes)
 }
 
 func humanSize(i int64) string {
@@ -194,9 +589,50 @@ func humanSize(i int64) string {
 	return fmt.Sprintf("%d%s", i, uf[ui])
 }
 
-func getHugepageSizes() ([]string, error) {
+// FindCgroupMountpoints exposes the cgroup v1 per-controller mountpoint
+// lookup so other packages, such as pkg/sysinfo/metrics, can resolve a
+// container's cgroup paths without duplicating the logic.
+func FindCgroupMountpoints() (map[string]string, error) {
+	return findCgroupMountpoints()
+}
+
+// HugePageSizes is the set of hugepage sizes (e.g. "2MB") supported by the
+// kernel, computed once at package init. It is read from
+// /sys/kernel/mm/hugepages, which — unlike the hugetlb cgroup — is available
+// regardless of cgroup version and doesn't require the hugetlb controller to
+// be mounted, so looking it up no longer costs a cgroupfs walk on every
+// ValidateHugetlb call.
+var HugePageSizes = hugePageSizes()
+
+// hugePageSizes lists the hugepage sizes supported by the kernel from
+// /sys/kernel/mm/hugepages, falling back to the legacy hugetlb cgroup scan
+// (cgroupHugepageSizes) when that directory can't be read.
+func hugePageSizes() []string {
+	dirs, err := os.ReadDir(hugepagesRoot)
+	if err != nil {
+		hps, err := cgroupHugepageSizes()
+		if err != nil {
+			return nil
+		}
+		return hps
+	}
+
 	var hps []string
+	for _, d := range dirs {
+		sizeBytes, ok := hugepageDirSize(d.Name())
+		if !ok {
+			continue
+		}
+		hps = append(hps, humanSize(int64(sizeBytes)))
+	}
+	return hps
+}
 
+// cgroupHugepageSizes is the legacy hugetlb cgroup scan: it enumerates
+// hugetlb.<size>.limit_in_bytes files under the hugetlb cgroup mountpoint.
+// It now only runs as a fallback when /sys/kernel/mm/hugepages is
+// unreadable.
+func cgroupHugepageSizes() ([]string, error) {
 	cgMounts, err := findCgroupMountpoints()
 	if err != nil {
 		return nil, err
@@ -206,30 +642,22 @@ func getHugepageSizes() ([]string, error) {
 		return nil, fmt.Errorf("Hugetlb cgroup not supported")
 	}
 
-	f, err := os.Open(hgtlbMp)
+	hps, err := hugepageSizesFromCgroup(hgtlbMp, "limit_in_bytes")
 	if err != nil {
-		return nil, fmt.Errorf("Failed to open hugetlb cgroup directory")
-	}
-	// -1 here means to read all the fileInfo from the directory, could be any negative number
-	fi, err := f.Readdir(-1)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to read hugetlb cgroup directory")
+		return nil, err
 	}
+	return hps, nil
+}
 
-	for _, finfo := range fi {
-		if strings.Contains(finfo.Name(), "limit_in_bytes") {
-			sres := strings.SplitN(finfo.Name(), ".", 3)
-			if len(sres) != 3 {
-				continue
-			}
-			hps = append(hps, sres[1])
-		}
-	}
-	if len(hps) == 0 {
+// getHugepageSizes is kept as a thin wrapper around the cached
+// HugePageSizes for backward compatibility.
+//
+// Deprecated: use the HugePageSizes package variable directly.
+func getHugepageSizes() ([]string, error) {
+	if len(HugePageSizes) == 0 {
 		return nil, fmt.Errorf("Hugetlb pagesize not found in cgroup")
 	}
-
-	return hps, nil
+	return HugePageSizes, nil
 }
 
 // GetDefaultHugepageSize returns system default hugepage size
@@ -289,12 +717,119 @@ func isCpusetListAvailable(provided, available string) (bool, error) {
 	return true, nil
 }
 
-// Returns bit count of 1, used by NumCPU
-func popcnt(x uint64) (n byte) {
-	x -= (x >> 1) & 0x5555555555555555
-	x = (x>>2)&0x3333333333333333 + x&0x3333333333333333
-	x += x >> 4
-	x &= 0x0f0f0f0f0f0f0f0f
-	x *= 0x0101010101010101
-	return byte(x >> 56)
+// NumCPU returns the number of CPUs online on the host, and the number of
+// CPUs actually available to this process once cpuset restrictions are
+// applied. The two differ whenever cgroups pin the calling container (or
+// process) to a subset of the host's CPUs; callers that need to size
+// worker pools or validate `--cpus` should use the latter.
+func NumCPU() (online, cpuset int) {
+	online = onlineCPUCount()
+	cpuset = cpusetCPUCount(online)
+	return online, cpuset
+}
+
+func onlineCPUCount() int {
+	list, err := os.ReadFile("/sys/devices/system/cpu/online")
+	if err != nil {
+		return runtime.NumCPU()
+	}
+	n, err := countCPUList(strings.TrimSpace(string(list)))
+	if err != nil {
+		return runtime.NumCPU()
+	}
+	return n
+}
+
+// cpusetCPUCount returns the number of CPUs available to this process under
+// its cpuset restriction, falling back to online when no restriction can be
+// determined.
+func cpusetCPUCount(online int) int {
+	list, ok := effectiveCpusetCPUs()
+	if !ok {
+		return online
+	}
+	n, err := countCPUList(list)
+	if err != nil {
+		return online
+	}
+	return n
+}
+
+// effectiveCpusetCPUs reads the calling process's own effective
+// cpuset.cpus, preferring the cgroup v2 unified hierarchy and falling back
+// to the legacy v1 cpuset controller mountpoint. It resolves the process's
+// cgroup sub-path from /proc/self/cgroup first, since the cpuset
+// restriction lives there rather than at the controller's root.
+func effectiveCpusetCPUs() (string, bool) {
+	if isCgroup2UnifiedMode() {
+		self, ok := selfCgroupPath("")
+		if !ok {
+			return "", false
+		}
+		dir := filepath.Join(unifiedMountpoint, self)
+		if list := readCgroupFile(dir, "cpuset.cpus.effective"); list != "" {
+			return list, true
+		}
+		return "", false
+	}
+
+	cgMounts, err := findCgroupMountpoints()
+	if err != nil {
+		return "", false
+	}
+	mp, ok := cgMounts["cpuset"]
+	if !ok {
+		return "", false
+	}
+	self, ok := selfCgroupPath("cpuset")
+	if !ok {
+		return "", false
+	}
+	dir := filepath.Join(mp, self)
+	if list := readCgroupFile(dir, "cpuset.cpus"); list != "" {
+		return list, true
+	}
+	return "", false
+}
+
+// selfCgroupPath returns the calling process's cgroup path for the given
+// v1 controller (or the unified v2 path when controller is ""), as found
+// in /proc/self/cgroup.
+func selfCgroupPath(controller string) (string, bool) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.SplitN(s.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if controller == "" {
+			if fields[0] == "0" && fields[1] == "" {
+				return fields[2], true
+			}
+			continue
+		}
+		for _, c := range strings.Split(fields[1], ",") {
+			if c == controller {
+				return fields[2], true
+			}
+		}
+	}
+	return "", false
+}
+
+// countCPUList counts the number of CPUs named in a kernel CPU list (e.g.
+// "0-3,8"). parsers.ParseUintList already expands the list into a set of
+// CPU ids, so the count is just its size.
+func countCPUList(list string) (int, error) {
+	ids, err := parsers.ParseUintList(list)
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
 }
@@ -0,0 +1,222 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestHugepageDirSize(t *testing.T) {
+	cases := []struct {
+		name      string
+		wantBytes uint64
+		wantOK    bool
+	}{
+		{"hugepages-2048kB", 2048 * 1024, true},
+		{"hugepages-1048576kB", 1048576 * 1024, true},
+		{"hugepages-2048", 0, false},
+		{"not-a-hugepages-dir", 0, false},
+		{"hugepages-abckB", 0, false},
+	}
+	for _, c := range cases {
+		gotBytes, gotOK := hugepageDirSize(c.name)
+		if gotOK != c.wantOK || gotBytes != c.wantBytes {
+			t.Errorf("hugepageDirSize(%q) = (%d, %v), want (%d, %v)", c.name, gotBytes, gotOK, c.wantBytes, c.wantOK)
+		}
+	}
+}
+
+func TestNodeDirNode(t *testing.T) {
+	cases := []struct {
+		name   string
+		wantN  int
+		wantOK bool
+	}{
+		{"node0", 0, true},
+		{"node12", 12, true},
+		{"cpu0", 0, false},
+		{"node", 0, false},
+	}
+	for _, c := range cases {
+		gotN, gotOK := nodeDirNode(c.name)
+		if gotOK != c.wantOK || gotN != c.wantN {
+			t.Errorf("nodeDirNode(%q) = (%d, %v), want (%d, %v)", c.name, gotN, gotOK, c.wantN, c.wantOK)
+		}
+	}
+}
+
+// writeHugepageDir creates a hugepages-<kB>kB directory with the given
+// counters, under root.
+func writeHugepageDir(t *testing.T, root string, sizeKB int, nr, free, surplus, resv int64) {
+	t.Helper()
+	dir := filepath.Join(root, "hugepages-"+strconv.Itoa(sizeKB)+"kB")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]int64{
+		"nr_hugepages":      nr,
+		"free_hugepages":    free,
+		"surplus_hugepages": surplus,
+		"resv_hugepages":    resv,
+	}
+	for name, v := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(strconv.FormatInt(v, 10)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHugePages(t *testing.T) {
+	oldHugepagesRoot, oldNodeRoot := hugepagesRoot, nodeRoot
+	defer func() { hugepagesRoot, nodeRoot = oldHugepagesRoot, oldNodeRoot }()
+
+	root := t.TempDir()
+	hugepagesRoot = filepath.Join(root, "hugepages")
+	nodeRoot = filepath.Join(root, "node")
+
+	writeHugepageDir(t, hugepagesRoot, 2048, 10, 4, 0, 2)
+	writeHugepageDir(t, filepath.Join(nodeRoot, "node0", "hugepages"), 2048, 6, 2, 0, 0)
+	writeHugepageDir(t, filepath.Join(nodeRoot, "node1", "hugepages"), 2048, 4, 2, 0, 0)
+
+	infos := hugePages()
+
+	var system, node0, node1 *HugePageInfo
+	for i := range infos {
+		switch infos[i].NUMANode {
+		case -1:
+			system = &infos[i]
+		case 0:
+			node0 = &infos[i]
+		case 1:
+			node1 = &infos[i]
+		}
+	}
+
+	if system == nil || system.Total != 10 || system.Free != 4 || system.Reserved != 2 {
+		t.Fatalf("system-wide entry = %+v, want Total=10 Free=4 Reserved=2", system)
+	}
+	if node0 == nil || node0.Total != 6 || node0.Free != 2 || node0.Reserved != 0 {
+		t.Fatalf("node0 entry = %+v, want Total=6 Free=2 Reserved=0", node0)
+	}
+	if node1 == nil || node1.Total != 4 || node1.Free != 2 {
+		t.Fatalf("node1 entry = %+v, want Total=4 Free=2", node1)
+	}
+}
+
+func TestHugePageSizesFallsBackWhenSysfsUnreadable(t *testing.T) {
+	oldHugepagesRoot := hugepagesRoot
+	defer func() { hugepagesRoot = oldHugepagesRoot }()
+
+	hugepagesRoot = filepath.Join(t.TempDir(), "does-not-exist")
+
+	// With no cgroup hugetlb mount reachable either, hugePageSizes should
+	// fail closed (empty slice) rather than panic.
+	if got := hugePageSizes(); len(got) != 0 {
+		t.Errorf("hugePageSizes() = %v, want empty when sysfs is unreadable and no cgroup fallback exists", got)
+	}
+}
+
+func TestHugePageSizesFromSysfs(t *testing.T) {
+	oldHugepagesRoot := hugepagesRoot
+	defer func() { hugepagesRoot = oldHugepagesRoot }()
+
+	root := t.TempDir()
+	hugepagesRoot = root
+	writeHugepageDir(t, root, 2048, 1, 1, 0, 0)
+	writeHugepageDir(t, root, 1048576, 0, 0, 0, 0)
+
+	got := hugePageSizes()
+	want := map[string]bool{"2MB": true, "1GB": true}
+	if len(got) != len(want) {
+		t.Fatalf("hugePageSizes() = %v, want sizes %v", got, want)
+	}
+	for _, size := range got {
+		if !want[size] {
+			t.Errorf("hugePageSizes() contains unexpected size %q", size)
+		}
+	}
+}
+
+func TestHugepageSizesFromCgroupDedupsReservationFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"hugetlb.2MB.limit_in_bytes",
+		"hugetlb.2MB.rsvd.limit_in_bytes",
+		"hugetlb.1GB.limit_in_bytes",
+		"hugetlb.2MB.max", // different suffix, should be ignored
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("-1"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := hugepageSizesFromCgroup(dir, "limit_in_bytes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{"2MB": true, "1GB": true}
+	if len(got) != len(want) {
+		t.Fatalf("hugepageSizesFromCgroup() = %v, want one entry per size %v", got, want)
+	}
+	for _, size := range got {
+		if !want[size] {
+			t.Errorf("hugepageSizesFromCgroup() contains unexpected size %q", size)
+		}
+	}
+}
+
+func TestCheckHugetlbAvailability(t *testing.T) {
+	const twoMB = 2 * 1024 * 1024
+
+	cases := []struct {
+		name      string
+		hugePages []HugePageInfo
+		limit     uint64
+		wantWarn  bool
+	}{
+		{
+			name: "fits within per-node free pages",
+			hugePages: []HugePageInfo{
+				{SizeBytes: twoMB, NUMANode: 0, Free: 5},
+				{SizeBytes: twoMB, NUMANode: 1, Free: 5},
+			},
+			limit:    8 * twoMB,
+			wantWarn: false,
+		},
+		{
+			name: "exceeds per-node free pages",
+			hugePages: []HugePageInfo{
+				{SizeBytes: twoMB, NUMANode: 0, Free: 1},
+				{SizeBytes: twoMB, NUMANode: 1, Free: 1},
+			},
+			limit:    8 * twoMB,
+			wantWarn: true,
+		},
+		{
+			name: "falls back to system-wide entry when no per-node data",
+			hugePages: []HugePageInfo{
+				{SizeBytes: twoMB, NUMANode: -1, Free: 1},
+			},
+			limit:    8 * twoMB,
+			wantWarn: true,
+		},
+		{
+			name:      "unknown inventory yields no warning",
+			hugePages: nil,
+			limit:     8 * twoMB,
+			wantWarn:  false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			info := SysInfo{HugePages: c.hugePages}
+			got := info.checkHugetlbAvailability("2MB", c.limit)
+			if (got != "") != c.wantWarn {
+				t.Errorf("checkHugetlbAvailability() = %q, wantWarn=%v", got, c.wantWarn)
+			}
+		})
+	}
+}